@@ -0,0 +1,271 @@
+package selenium
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// action is the wire representation of a single tick of an InputSource.
+type action map[string]interface{}
+
+// InputSource is one parallel input device (pointer, key, or wheel) taking
+// part in a PerformActions call. Build one with NewPointerInput,
+// NewKeyInput, or NewWheelInput, append ticks to it, then pass the result
+// to PerformActions.
+type InputSource interface {
+	source() map[string]interface{}
+}
+
+// Origin is where a pointerMove's or scroll's x/y coordinates are measured
+// from: OriginViewport or OriginPointer. A WebElement may be passed instead
+// wherever an origin is accepted, to measure from that element's center.
+type Origin string
+
+// Origins recognized by the remote end besides a WebElement.
+const (
+	OriginViewport Origin = "viewport"
+	OriginPointer  Origin = "pointer"
+)
+
+func wireOrigin(origin interface{}) interface{} {
+	if we, ok := origin.(*remoteWE); ok {
+		return elementRef{Id: we.id}
+	}
+	return origin
+}
+
+// PointerType selects the kind of pointer a PointerInput simulates.
+type PointerType string
+
+// Pointer kinds understood by the remote end.
+const (
+	PointerMouse PointerType = "mouse"
+	PointerPen   PointerType = "pen"
+	PointerTouch PointerType = "touch"
+)
+
+// PointerInput builds a sequence of pointer actions (move, down, up, pause)
+// for use with PerformActions.
+type PointerInput struct {
+	id      string
+	kind    PointerType
+	actions []action
+}
+
+// NewPointerInput returns an empty PointerInput of the given kind. id need
+// only be unique within a single PerformActions call; actions from
+// different InputSources sharing a tick index run concurrently.
+func NewPointerInput(kind PointerType, id string) *PointerInput {
+	return &PointerInput{id: id, kind: kind}
+}
+
+func (p *PointerInput) source() map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "pointer",
+		"id":         p.id,
+		"parameters": map[string]interface{}{"pointerType": string(p.kind)},
+		"actions":    p.actions,
+	}
+}
+
+// Move adds a pointerMove tick to (x, y), relative to origin, over
+// duration. origin is OriginViewport, OriginPointer, or a WebElement.
+func (p *PointerInput) Move(x, y int, origin interface{}, duration time.Duration) *PointerInput {
+	p.actions = append(p.actions, action{
+		"type": "pointerMove", "x": x, "y": y,
+		"origin": wireOrigin(origin), "duration": duration.Milliseconds(),
+	})
+	return p
+}
+
+// Down adds a pointerDown tick, pressing button (0 is the primary button).
+func (p *PointerInput) Down(button int) *PointerInput {
+	p.actions = append(p.actions, action{"type": "pointerDown", "button": button})
+	return p
+}
+
+// Up adds a pointerUp tick, releasing button.
+func (p *PointerInput) Up(button int) *PointerInput {
+	p.actions = append(p.actions, action{"type": "pointerUp", "button": button})
+	return p
+}
+
+// Pause adds a tick in which this input source does nothing for duration.
+func (p *PointerInput) Pause(duration time.Duration) *PointerInput {
+	p.actions = append(p.actions, action{"type": "pause", "duration": duration.Milliseconds()})
+	return p
+}
+
+// KeyInput builds a sequence of key actions (keyDown, keyUp, pause) for use
+// with PerformActions.
+type KeyInput struct {
+	id      string
+	actions []action
+}
+
+// NewKeyInput returns an empty KeyInput. id need only be unique within a
+// single PerformActions call.
+func NewKeyInput(id string) *KeyInput {
+	return &KeyInput{id: id}
+}
+
+func (k *KeyInput) source() map[string]interface{} {
+	return map[string]interface{}{"type": "key", "id": k.id, "actions": k.actions}
+}
+
+// KeyDown adds a keyDown tick for value (a single character, or one of the
+// WebDriver normalized key values such as "" for Enter).
+func (k *KeyInput) KeyDown(value string) *KeyInput {
+	k.actions = append(k.actions, action{"type": "keyDown", "value": value})
+	return k
+}
+
+// KeyUp adds a keyUp tick for value.
+func (k *KeyInput) KeyUp(value string) *KeyInput {
+	k.actions = append(k.actions, action{"type": "keyUp", "value": value})
+	return k
+}
+
+// Pause adds a tick in which this input source does nothing for duration.
+func (k *KeyInput) Pause(duration time.Duration) *KeyInput {
+	k.actions = append(k.actions, action{"type": "pause", "duration": duration.Milliseconds()})
+	return k
+}
+
+// WheelInput builds a sequence of scroll actions for use with
+// PerformActions.
+type WheelInput struct {
+	id      string
+	actions []action
+}
+
+// NewWheelInput returns an empty WheelInput. id need only be unique within
+// a single PerformActions call.
+func NewWheelInput(id string) *WheelInput {
+	return &WheelInput{id: id}
+}
+
+func (w *WheelInput) source() map[string]interface{} {
+	return map[string]interface{}{"type": "wheel", "id": w.id, "actions": w.actions}
+}
+
+// Scroll adds a scroll tick at (x, y), relative to origin, scrolling by
+// (deltaX, deltaY) over duration. origin is OriginViewport, OriginPointer,
+// or a WebElement.
+func (w *WheelInput) Scroll(x, y, deltaX, deltaY int, origin interface{}, duration time.Duration) *WheelInput {
+	w.actions = append(w.actions, action{
+		"type": "scroll", "x": x, "y": y, "deltaX": deltaX, "deltaY": deltaY,
+		"origin": wireOrigin(origin), "duration": duration.Milliseconds(),
+	})
+	return w
+}
+
+// PerformActions sends a W3C Actions sequence to the remote end, ticking
+// every InputSource in lockstep.
+func (wd *remoteWebDriver) PerformActions(actions []InputSource) error {
+	return wd.PerformActionsContext(context.Background(), actions)
+}
+
+// PerformActionsContext is PerformActions's context-aware counterpart.
+func (wd *remoteWebDriver) PerformActionsContext(ctx context.Context, actions []InputSource) error {
+	sources := make([]map[string]interface{}, len(actions))
+	for i, a := range actions {
+		sources[i] = a.source()
+	}
+	data, err := json.Marshal(map[string]interface{}{"actions": sources})
+	if err != nil {
+		return err
+	}
+	_, err = wd.doContext(ctx, "POST", wd.requestURL("/session/%s/actions", wd.id), data)
+	return err
+}
+
+// ReleaseActions releases all keys and pointer buttons currently held down
+// as a result of PerformActions.
+func (wd *remoteWebDriver) ReleaseActions() error {
+	return wd.ReleaseActionsContext(context.Background())
+}
+
+// ReleaseActionsContext is ReleaseActions's context-aware counterpart.
+func (wd *remoteWebDriver) ReleaseActionsContext(ctx context.Context) error {
+	_, err := wd.doContext(ctx, "DELETE", wd.requestURL("/session/%s/actions", wd.id), nil)
+	return err
+}
+
+// DragAndDrop drags src to dst: pointer-down on src, move to dst, pointer-up.
+func (wd *remoteWebDriver) DragAndDrop(src, dst WebElement) error {
+	return wd.DragAndDropContext(context.Background(), src, dst)
+}
+
+// DragAndDropContext is DragAndDrop's context-aware counterpart.
+func (wd *remoteWebDriver) DragAndDropContext(ctx context.Context, src, dst WebElement) error {
+	srcLoc, err := locationInViewContext(ctx, src)
+	if err != nil {
+		return err
+	}
+	dstLoc, err := locationInViewContext(ctx, dst)
+	if err != nil {
+		return err
+	}
+
+	pointer := NewPointerInput(PointerMouse, "mouse").
+		Move(srcLoc.X, srcLoc.Y, OriginViewport, 0).
+		Down(0).
+		Move(dstLoc.X, dstLoc.Y, OriginViewport, 200*time.Millisecond).
+		Up(0)
+
+	if err := wd.PerformActionsContext(ctx, []InputSource{pointer}); err != nil {
+		return err
+	}
+	return wd.ReleaseActionsContext(ctx)
+}
+
+// locationInViewContext fetches elem's viewport-relative location, using
+// elem's context-aware counterpart when available.
+func locationInViewContext(ctx context.Context, elem WebElement) (*Point, error) {
+	if ce, ok := elem.(ContextWebElement); ok {
+		return ce.LocationInViewContext(ctx)
+	}
+	return elem.LocationInView()
+}
+
+// DoubleClick performs a synthesized double-click on elem.
+func (wd *remoteWebDriver) DoubleClick(elem WebElement) error {
+	return wd.DoubleClickContext(context.Background(), elem)
+}
+
+// DoubleClickContext is DoubleClick's context-aware counterpart.
+func (wd *remoteWebDriver) DoubleClickContext(ctx context.Context, elem WebElement) error {
+	pointer := NewPointerInput(PointerMouse, "mouse").
+		Move(0, 0, elem, 0).
+		Down(0).Up(0).
+		Down(0).Up(0)
+
+	if err := wd.PerformActionsContext(ctx, []InputSource{pointer}); err != nil {
+		return err
+	}
+	return wd.ReleaseActionsContext(ctx)
+}
+
+// HoldKey presses key down and returns a function that releases it; callers
+// should defer the returned function so the key is never left stuck down.
+func (wd *remoteWebDriver) HoldKey(key string) (release func() error, err error) {
+	return wd.HoldKeyContext(context.Background(), key)
+}
+
+// HoldKeyContext is HoldKey's context-aware counterpart. Both the initial
+// key-down and the returned release function are bounded by ctx.
+func (wd *remoteWebDriver) HoldKeyContext(ctx context.Context, key string) (release func() error, err error) {
+	down := NewKeyInput("keyboard").KeyDown(key)
+	if err := wd.PerformActionsContext(ctx, []InputSource{down}); err != nil {
+		return nil, err
+	}
+	return func() error {
+		up := NewKeyInput("keyboard").KeyUp(key)
+		if err := wd.PerformActionsContext(ctx, []InputSource{up}); err != nil {
+			return err
+		}
+		return wd.ReleaseActionsContext(ctx)
+	}, nil
+}
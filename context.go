@@ -0,0 +1,555 @@
+package selenium
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// ContextWebDriver is implemented by WebDriver values that additionally
+// accept a context.Context on every call, so a caller can cancel or bound
+// an in-flight request (a hung Click, a long ExecuteAsyncScript) without
+// leaking the underlying HTTP round-trip. remoteWebDriver satisfies this.
+type ContextWebDriver interface {
+	StatusContext(ctx context.Context) (*Status, error)
+	CapabilitiesContext(ctx context.Context) (Capabilities, error)
+
+	SetAsyncScriptTimeoutContext(ctx context.Context, ms uint) error
+	SetImplicitWaitTimeoutContext(ctx context.Context, ms uint) error
+
+	CurrentWindowHandleContext(ctx context.Context) (string, error)
+	WindowHandlesContext(ctx context.Context) ([]string, error)
+
+	GetContext(ctx context.Context, url string) error
+	CurrentURLContext(ctx context.Context) (string, error)
+	TitleContext(ctx context.Context) (string, error)
+	PageSourceContext(ctx context.Context) (string, error)
+	BackContext(ctx context.Context) error
+	ForwardContext(ctx context.Context) error
+	RefreshContext(ctx context.Context) error
+
+	FindElementContext(ctx context.Context, by By, value string) (WebElement, error)
+	FindElementsContext(ctx context.Context, by By, value string) ([]WebElement, error)
+
+	GetCookiesContext(ctx context.Context) ([]Cookie, error)
+	AddCookieContext(ctx context.Context, cookie *Cookie) error
+	DeleteCookieContext(ctx context.Context, name string) error
+
+	ExecuteScriptContext(ctx context.Context, script string, args []interface{}) (interface{}, error)
+	ExecuteAsyncScriptContext(ctx context.Context, script string, args []interface{}) (interface{}, error)
+
+	ScreenshotContext(ctx context.Context) ([]byte, error)
+	ScreenshotRectContext(ctx context.Context, rect Rect) ([]byte, error)
+
+	PerformActionsContext(ctx context.Context, actions []InputSource) error
+	ReleaseActionsContext(ctx context.Context) error
+	DragAndDropContext(ctx context.Context, src, dst WebElement) error
+	DoubleClickContext(ctx context.Context, elem WebElement) error
+	HoldKeyContext(ctx context.Context, key string) (release func() error, err error)
+
+	QuitContext(ctx context.Context) error
+
+	// WithTimeout returns a WebDriver whose *Context methods, when called
+	// through their non-context counterparts, are each bounded by d.
+	WithTimeout(d time.Duration) WebDriver
+}
+
+// ContextWebElement is implemented by WebElement values that additionally
+// accept a context.Context on every call. remoteWE satisfies this.
+type ContextWebElement interface {
+	ClickContext(ctx context.Context) error
+	SendKeysContext(ctx context.Context, keys string) error
+	TextContext(ctx context.Context) (string, error)
+	IsSelectedContext(ctx context.Context) (bool, error)
+	LocationContext(ctx context.Context) (*Point, error)
+	LocationInViewContext(ctx context.Context) (*Point, error)
+	SizeContext(ctx context.Context) (*Size, error)
+	ScreenshotContext(ctx context.Context) ([]byte, error)
+}
+
+// WithTimeout returns a WebDriver that wraps wd and applies d as a deadline
+// to every call made through it. The returned value is otherwise a normal
+// WebDriver; it shares wd's session.
+func (wd *remoteWebDriver) WithTimeout(d time.Duration) WebDriver {
+	return &timeoutWebDriver{remoteWebDriver: wd, timeout: d}
+}
+
+// timeoutWebDriver wraps a remoteWebDriver so every request it issues is
+// bounded by timeout, without requiring callers to thread a context.Context
+// through manually.
+type timeoutWebDriver struct {
+	*remoteWebDriver
+	timeout time.Duration
+}
+
+func (wd *timeoutWebDriver) withDeadline() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), wd.timeout)
+}
+
+func (wd *timeoutWebDriver) Status() (*Status, error) {
+	ctx, cancel := wd.withDeadline()
+	defer cancel()
+	return wd.StatusContext(ctx)
+}
+
+func (wd *timeoutWebDriver) Capabilities() (Capabilities, error) {
+	ctx, cancel := wd.withDeadline()
+	defer cancel()
+	return wd.CapabilitiesContext(ctx)
+}
+
+func (wd *timeoutWebDriver) SetAsyncScriptTimeout(ms uint) error {
+	ctx, cancel := wd.withDeadline()
+	defer cancel()
+	return wd.SetAsyncScriptTimeoutContext(ctx, ms)
+}
+
+func (wd *timeoutWebDriver) SetImplicitWaitTimeout(ms uint) error {
+	ctx, cancel := wd.withDeadline()
+	defer cancel()
+	return wd.SetImplicitWaitTimeoutContext(ctx, ms)
+}
+
+func (wd *timeoutWebDriver) CurrentWindowHandle() (string, error) {
+	ctx, cancel := wd.withDeadline()
+	defer cancel()
+	return wd.CurrentWindowHandleContext(ctx)
+}
+
+func (wd *timeoutWebDriver) WindowHandles() ([]string, error) {
+	ctx, cancel := wd.withDeadline()
+	defer cancel()
+	return wd.WindowHandlesContext(ctx)
+}
+
+func (wd *timeoutWebDriver) Get(url string) error {
+	ctx, cancel := wd.withDeadline()
+	defer cancel()
+	return wd.GetContext(ctx, url)
+}
+
+func (wd *timeoutWebDriver) CurrentURL() (string, error) {
+	ctx, cancel := wd.withDeadline()
+	defer cancel()
+	return wd.CurrentURLContext(ctx)
+}
+
+func (wd *timeoutWebDriver) Title() (string, error) {
+	ctx, cancel := wd.withDeadline()
+	defer cancel()
+	return wd.TitleContext(ctx)
+}
+
+func (wd *timeoutWebDriver) PageSource() (string, error) {
+	ctx, cancel := wd.withDeadline()
+	defer cancel()
+	return wd.PageSourceContext(ctx)
+}
+
+func (wd *timeoutWebDriver) Back() error {
+	ctx, cancel := wd.withDeadline()
+	defer cancel()
+	return wd.BackContext(ctx)
+}
+
+func (wd *timeoutWebDriver) Forward() error {
+	ctx, cancel := wd.withDeadline()
+	defer cancel()
+	return wd.ForwardContext(ctx)
+}
+
+func (wd *timeoutWebDriver) Refresh() error {
+	ctx, cancel := wd.withDeadline()
+	defer cancel()
+	return wd.RefreshContext(ctx)
+}
+
+func (wd *timeoutWebDriver) FindElement(by By, value string) (WebElement, error) {
+	ctx, cancel := wd.withDeadline()
+	defer cancel()
+	return wd.FindElementContext(ctx, by, value)
+}
+
+func (wd *timeoutWebDriver) FindElements(by By, value string) ([]WebElement, error) {
+	ctx, cancel := wd.withDeadline()
+	defer cancel()
+	return wd.FindElementsContext(ctx, by, value)
+}
+
+func (wd *timeoutWebDriver) GetCookies() ([]Cookie, error) {
+	ctx, cancel := wd.withDeadline()
+	defer cancel()
+	return wd.GetCookiesContext(ctx)
+}
+
+func (wd *timeoutWebDriver) AddCookie(cookie *Cookie) error {
+	ctx, cancel := wd.withDeadline()
+	defer cancel()
+	return wd.AddCookieContext(ctx, cookie)
+}
+
+func (wd *timeoutWebDriver) DeleteCookie(name string) error {
+	ctx, cancel := wd.withDeadline()
+	defer cancel()
+	return wd.DeleteCookieContext(ctx, name)
+}
+
+func (wd *timeoutWebDriver) ExecuteScript(script string, args []interface{}) (interface{}, error) {
+	ctx, cancel := wd.withDeadline()
+	defer cancel()
+	return wd.ExecuteScriptContext(ctx, script, args)
+}
+
+func (wd *timeoutWebDriver) ExecuteAsyncScript(script string, args []interface{}) (interface{}, error) {
+	ctx, cancel := wd.withDeadline()
+	defer cancel()
+	return wd.ExecuteAsyncScriptContext(ctx, script, args)
+}
+
+func (wd *timeoutWebDriver) Screenshot() ([]byte, error) {
+	ctx, cancel := wd.withDeadline()
+	defer cancel()
+	return wd.ScreenshotContext(ctx)
+}
+
+func (wd *timeoutWebDriver) ScreenshotRect(rect Rect) ([]byte, error) {
+	ctx, cancel := wd.withDeadline()
+	defer cancel()
+	return wd.ScreenshotRectContext(ctx, rect)
+}
+
+func (wd *timeoutWebDriver) PerformActions(actions []InputSource) error {
+	ctx, cancel := wd.withDeadline()
+	defer cancel()
+	return wd.PerformActionsContext(ctx, actions)
+}
+
+func (wd *timeoutWebDriver) ReleaseActions() error {
+	ctx, cancel := wd.withDeadline()
+	defer cancel()
+	return wd.ReleaseActionsContext(ctx)
+}
+
+func (wd *timeoutWebDriver) DragAndDrop(src, dst WebElement) error {
+	ctx, cancel := wd.withDeadline()
+	defer cancel()
+	return wd.DragAndDropContext(ctx, src, dst)
+}
+
+func (wd *timeoutWebDriver) DoubleClick(elem WebElement) error {
+	ctx, cancel := wd.withDeadline()
+	defer cancel()
+	return wd.DoubleClickContext(ctx, elem)
+}
+
+// HoldKey presses key down under its own deadline and returns a release
+// function bounded by a fresh deadline of its own, since release is meant to
+// be called later and must not inherit a context already canceled by this
+// method's return.
+func (wd *timeoutWebDriver) HoldKey(key string) (func() error, error) {
+	ctx, cancel := wd.withDeadline()
+	defer cancel()
+	down := NewKeyInput("keyboard").KeyDown(key)
+	if err := wd.PerformActionsContext(ctx, []InputSource{down}); err != nil {
+		return nil, err
+	}
+	return func() error {
+		ctx, cancel := wd.withDeadline()
+		defer cancel()
+		up := NewKeyInput("keyboard").KeyUp(key)
+		if err := wd.PerformActionsContext(ctx, []InputSource{up}); err != nil {
+			return err
+		}
+		return wd.ReleaseActionsContext(ctx)
+	}, nil
+}
+
+func (wd *timeoutWebDriver) Quit() error {
+	ctx, cancel := wd.withDeadline()
+	defer cancel()
+	return wd.QuitContext(ctx)
+}
+
+func (wd *remoteWebDriver) StatusContext(ctx context.Context) (*Status, error) {
+	status := new(Status)
+	err := wd.decodeContext(ctx, "GET", wd.executor+"/status", nil, status)
+	return status, err
+}
+
+func (wd *remoteWebDriver) CapabilitiesContext(ctx context.Context) (Capabilities, error) {
+	c := make(Capabilities)
+	err := wd.decodeContext(ctx, "GET", wd.requestURL("/session/%s", wd.id), nil, &c)
+	return c, err
+}
+
+func (wd *remoteWebDriver) SetAsyncScriptTimeoutContext(ctx context.Context, ms uint) error {
+	data, err := json.Marshal(map[string]uint{"ms": ms})
+	if err != nil {
+		return err
+	}
+	_, err = wd.doContext(ctx, "POST", wd.requestURL("/session/%s/timeouts/async_script", wd.id), data)
+	return err
+}
+
+func (wd *remoteWebDriver) SetImplicitWaitTimeoutContext(ctx context.Context, ms uint) error {
+	data, err := json.Marshal(map[string]uint{"ms": ms})
+	if err != nil {
+		return err
+	}
+	_, err = wd.doContext(ctx, "POST", wd.requestURL("/session/%s/timeouts/implicit_wait", wd.id), data)
+	return err
+}
+
+func (wd *remoteWebDriver) CurrentWindowHandleContext(ctx context.Context) (string, error) {
+	var handle string
+	err := wd.decodeContext(ctx, "GET", wd.requestURL("/session/%s/window_handle", wd.id), nil, &handle)
+	return handle, err
+}
+
+func (wd *remoteWebDriver) WindowHandlesContext(ctx context.Context) ([]string, error) {
+	var handles []string
+	err := wd.decodeContext(ctx, "GET", wd.requestURL("/session/%s/window_handles", wd.id), nil, &handles)
+	return handles, err
+}
+
+// GetContext navigates to url, aborting the request if ctx is done first.
+func (wd *remoteWebDriver) GetContext(ctx context.Context, url string) error {
+	data, err := json.Marshal(map[string]string{"url": url})
+	if err != nil {
+		return err
+	}
+	_, err = wd.doContext(ctx, "POST", wd.requestURL("/session/%s/url", wd.id), data)
+	return err
+}
+
+func (wd *remoteWebDriver) CurrentURLContext(ctx context.Context) (string, error) {
+	var url string
+	err := wd.decodeContext(ctx, "GET", wd.requestURL("/session/%s/url", wd.id), nil, &url)
+	return url, err
+}
+
+func (wd *remoteWebDriver) TitleContext(ctx context.Context) (string, error) {
+	var title string
+	err := wd.decodeContext(ctx, "GET", wd.requestURL("/session/%s/title", wd.id), nil, &title)
+	return title, err
+}
+
+func (wd *remoteWebDriver) PageSourceContext(ctx context.Context) (string, error) {
+	var source string
+	err := wd.decodeContext(ctx, "GET", wd.requestURL("/session/%s/source", wd.id), nil, &source)
+	return source, err
+}
+
+func (wd *remoteWebDriver) BackContext(ctx context.Context) error {
+	_, err := wd.doContext(ctx, "POST", wd.requestURL("/session/%s/back", wd.id), nil)
+	return err
+}
+
+func (wd *remoteWebDriver) ForwardContext(ctx context.Context) error {
+	_, err := wd.doContext(ctx, "POST", wd.requestURL("/session/%s/forward", wd.id), nil)
+	return err
+}
+
+func (wd *remoteWebDriver) RefreshContext(ctx context.Context) error {
+	_, err := wd.doContext(ctx, "POST", wd.requestURL("/session/%s/refresh", wd.id), nil)
+	return err
+}
+
+func (wd *remoteWebDriver) FindElementContext(ctx context.Context, by By, value string) (WebElement, error) {
+	data, err := json.Marshal(map[string]string{"using": string(by), "value": value})
+	if err != nil {
+		return nil, err
+	}
+	var ref elementRef
+	if err := wd.decodeContext(ctx, "POST", wd.requestURL("/session/%s/element", wd.id), data, &ref); err != nil {
+		return nil, err
+	}
+	return &remoteWE{id: ref.Id, parent: wd}, nil
+}
+
+func (wd *remoteWebDriver) FindElementsContext(ctx context.Context, by By, value string) ([]WebElement, error) {
+	data, err := json.Marshal(map[string]string{"using": string(by), "value": value})
+	if err != nil {
+		return nil, err
+	}
+	var refs []elementRef
+	if err := wd.decodeContext(ctx, "POST", wd.requestURL("/session/%s/elements", wd.id), data, &refs); err != nil {
+		return nil, err
+	}
+	elems := make([]WebElement, len(refs))
+	for i, ref := range refs {
+		elems[i] = &remoteWE{id: ref.Id, parent: wd}
+	}
+	return elems, nil
+}
+
+func (wd *remoteWebDriver) GetCookiesContext(ctx context.Context) ([]Cookie, error) {
+	var cookies []Cookie
+	err := wd.decodeContext(ctx, "GET", wd.requestURL("/session/%s/cookie", wd.id), nil, &cookies)
+	return cookies, err
+}
+
+func (wd *remoteWebDriver) AddCookieContext(ctx context.Context, cookie *Cookie) error {
+	data, err := json.Marshal(map[string]*Cookie{"cookie": cookie})
+	if err != nil {
+		return err
+	}
+	_, err = wd.doContext(ctx, "POST", wd.requestURL("/session/%s/cookie", wd.id), data)
+	return err
+}
+
+func (wd *remoteWebDriver) DeleteCookieContext(ctx context.Context, name string) error {
+	_, err := wd.doContext(ctx, "DELETE", wd.requestURL("/session/%s/cookie/%s", wd.id, name), nil)
+	return err
+}
+
+func (wd *remoteWebDriver) ExecuteScriptContext(ctx context.Context, script string, args []interface{}) (interface{}, error) {
+	if args == nil {
+		args = make([]interface{}, 0)
+	}
+	data, err := json.Marshal(map[string]interface{}{"script": script, "args": args})
+	if err != nil {
+		return nil, err
+	}
+	var reply interface{}
+	err = wd.decodeContext(ctx, "POST", wd.requestURL("/session/%s/execute", wd.id), data, &reply)
+	return reply, err
+}
+
+// ExecuteAsyncScript runs script as an asynchronous script, waiting for its
+// callback (appended as the final argument) to be invoked. See
+// SetAsyncScriptTimeout for bounding how long the remote end waits.
+func (wd *remoteWebDriver) ExecuteAsyncScript(script string, args []interface{}) (interface{}, error) {
+	return wd.ExecuteAsyncScriptContext(context.Background(), script, args)
+}
+
+// ExecuteAsyncScriptContext is ExecuteAsyncScript's context-aware
+// counterpart: cancelling ctx aborts the underlying HTTP call, but does not
+// stop the script running server-side (use SetAsyncScriptTimeout for that).
+func (wd *remoteWebDriver) ExecuteAsyncScriptContext(ctx context.Context, script string, args []interface{}) (interface{}, error) {
+	if args == nil {
+		args = make([]interface{}, 0)
+	}
+	data, err := json.Marshal(map[string]interface{}{"script": script, "args": args})
+	if err != nil {
+		return nil, err
+	}
+	var reply interface{}
+	err = wd.decodeContext(ctx, "POST", wd.requestURL("/session/%s/execute_async", wd.id), data, &reply)
+	return reply, err
+}
+
+func (wd *remoteWebDriver) ScreenshotContext(ctx context.Context) ([]byte, error) {
+	var encoded string
+	if err := wd.decodeContext(ctx, "GET", wd.requestURL("/session/%s/screenshot", wd.id), nil, &encoded); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(encoded)
+}
+
+func (wd *remoteWebDriver) ScreenshotRectContext(ctx context.Context, rect Rect) ([]byte, error) {
+	full, err := wd.ScreenshotContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return cropPNG(full, rect)
+}
+
+func (wd *remoteWebDriver) QuitContext(ctx context.Context) error {
+	_, err := wd.doContext(ctx, "DELETE", wd.requestURL("/session/%s", wd.id), nil)
+	wd.id = ""
+	return err
+}
+
+// ClickContext is Click's context-aware counterpart.
+func (elem *remoteWE) ClickContext(ctx context.Context) error {
+	_, err := elem.parent.doContext(ctx, "POST", elem.url("/click"), nil)
+	return err
+}
+
+// SendKeysContext is SendKeys's context-aware counterpart.
+func (elem *remoteWE) SendKeysContext(ctx context.Context, keys string) error {
+	data, err := json.Marshal(map[string]interface{}{"value": strings.Split(keys, "")})
+	if err != nil {
+		return err
+	}
+	_, err = elem.parent.doContext(ctx, "POST", elem.url("/value"), data)
+	return err
+}
+
+// TextContext is Text's context-aware counterpart.
+func (elem *remoteWE) TextContext(ctx context.Context) (string, error) {
+	var text string
+	err := elem.parent.decodeContext(ctx, "GET", elem.url("/text"), nil, &text)
+	return text, err
+}
+
+// IsSelectedContext is IsSelected's context-aware counterpart.
+func (elem *remoteWE) IsSelectedContext(ctx context.Context) (bool, error) {
+	var selected bool
+	err := elem.parent.decodeContext(ctx, "GET", elem.url("/selected"), nil, &selected)
+	return selected, err
+}
+
+// LocationContext is Location's context-aware counterpart.
+func (elem *remoteWE) LocationContext(ctx context.Context) (*Point, error) {
+	p := new(Point)
+	err := elem.parent.decodeContext(ctx, "GET", elem.url("/location"), nil, p)
+	return p, err
+}
+
+// LocationInViewContext is LocationInView's context-aware counterpart.
+func (elem *remoteWE) LocationInViewContext(ctx context.Context) (*Point, error) {
+	p := new(Point)
+	err := elem.parent.decodeContext(ctx, "GET", elem.url("/location_in_view"), nil, p)
+	return p, err
+}
+
+// SizeContext is Size's context-aware counterpart.
+func (elem *remoteWE) SizeContext(ctx context.Context) (*Size, error) {
+	s := new(Size)
+	err := elem.parent.decodeContext(ctx, "GET", elem.url("/size"), nil, s)
+	return s, err
+}
+
+// ScreenshotContext is Screenshot's context-aware counterpart.
+func (elem *remoteWE) ScreenshotContext(ctx context.Context) ([]byte, error) {
+	loc, err := elem.LocationContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	size, err := elem.SizeContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return elem.parent.ScreenshotRectContext(ctx, Rect{Top: loc.Y, Left: loc.X, Width: size.Width, Height: size.Height})
+}
+
+// WaitConditionContext is a predicate polled by WaitWithContext.
+type WaitConditionContext func(ctx context.Context, wd WebDriver) (bool, error)
+
+// WaitWithContext polls cond every interval until it returns true, returns
+// an error, or ctx is done, whichever comes first. It is the context-aware
+// counterpart of a plain polling loop.
+func WaitWithContext(ctx context.Context, wd WebDriver, interval time.Duration, cond WaitConditionContext) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		done, err := cond(ctx, wd)
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,128 @@
+// Package cookiejar adapts the cookies of a live selenium.WebDriver session
+// to the net/http.CookieJar interface, so a Go http.Client can reuse
+// whatever cookies the browser already holds (and vice versa).
+package cookiejar
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/BenHall/go-selenium"
+)
+
+// WebDriverCookieJar implements http.CookieJar on top of a selenium.WebDriver
+// session. Reads and writes go straight through to the browser via
+// GetCookies/AddCookie/DeleteCookie, so an http.Client using this jar always
+// sees the browser's current cookies.
+type WebDriverCookieJar struct {
+	wd selenium.WebDriver
+}
+
+// New returns a CookieJar backed by wd's session.
+func New(wd selenium.WebDriver) *WebDriverCookieJar {
+	return &WebDriverCookieJar{wd: wd}
+}
+
+// SetCookies implements http.CookieJar.
+func (j *WebDriverCookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	for _, c := range cookies {
+		cookie := &selenium.Cookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Path:     cookiePath(u, c.Path),
+			Domain:   cookieDomain(u, c.Domain),
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+		}
+		if !c.Expires.IsZero() {
+			cookie.Expiry = c.Expires.Unix()
+		}
+		j.wd.AddCookie(cookie)
+	}
+}
+
+// Cookies implements http.CookieJar.
+func (j *WebDriverCookieJar) Cookies(u *url.URL) []*http.Cookie {
+	all, err := j.wd.GetCookies()
+	if err != nil {
+		return nil
+	}
+
+	host := canonicalHost(u)
+	var matched []*http.Cookie
+	for _, c := range all {
+		if !domainMatch(host, c.Domain) || !pathMatch(u.Path, c.Path) || expired(c.Expiry) {
+			continue
+		}
+		matched = append(matched, &http.Cookie{Name: c.Name, Value: c.Value, HttpOnly: c.HttpOnly})
+	}
+	return matched
+}
+
+// expired reports whether a Cookie.Expiry (a Unix timestamp that may arrive
+// as any JSON number type) is in the past. A missing or unparseable Expiry
+// never expires.
+func expired(expiry interface{}) bool {
+	var ts int64
+	switch v := expiry.(type) {
+	case int64:
+		ts = v
+	case int:
+		ts = int64(v)
+	case float64:
+		ts = int64(v)
+	default:
+		return false
+	}
+	return time.Unix(ts, 0).Before(time.Now())
+}
+
+// cookiePath mirrors net/http/cookiejar's default-path derivation: use the
+// cookie's own Path if it looks valid, otherwise derive one from the
+// request URL, as described in RFC 6265 section 5.1.4.
+func cookiePath(u *url.URL, path string) string {
+	if strings.HasPrefix(path, "/") {
+		return path
+	}
+	if i := strings.LastIndex(u.Path, "/"); i > 0 {
+		return u.Path[:i]
+	}
+	return "/"
+}
+
+// cookieDomain mirrors net/http/cookiejar's domain derivation: an explicit
+// Domain attribute (sans leading dot), or failing that, the request host.
+func cookieDomain(u *url.URL, domain string) string {
+	if domain == "" {
+		return canonicalHost(u)
+	}
+	return strings.TrimPrefix(domain, ".")
+}
+
+func canonicalHost(u *url.URL) string {
+	host := u.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(host)
+}
+
+func domainMatch(host, domain string) bool {
+	if domain == "" || host == domain {
+		return true
+	}
+	return strings.HasSuffix(host, "."+domain)
+}
+
+func pathMatch(reqPath, cookiePath string) bool {
+	if cookiePath == "" || cookiePath == "/" || reqPath == cookiePath {
+		return true
+	}
+	if strings.HasPrefix(reqPath, cookiePath) {
+		return cookiePath[len(cookiePath)-1] == '/' || reqPath[len(cookiePath)] == '/'
+	}
+	return false
+}
@@ -0,0 +1,181 @@
+package cookiejar
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/BenHall/go-selenium"
+)
+
+// fakeDriver is a minimal selenium.WebDriver backed by an in-memory cookie
+// store, just enough to exercise WebDriverCookieJar without a live browser.
+type fakeDriver struct {
+	cookies []selenium.Cookie
+}
+
+func (f *fakeDriver) Status() (*selenium.Status, error)            { return nil, nil }
+func (f *fakeDriver) Capabilities() (selenium.Capabilities, error) { return nil, nil }
+func (f *fakeDriver) SetAsyncScriptTimeout(ms uint) error          { return nil }
+func (f *fakeDriver) SetImplicitWaitTimeout(ms uint) error         { return nil }
+func (f *fakeDriver) CurrentWindowHandle() (string, error)         { return "", nil }
+func (f *fakeDriver) WindowHandles() ([]string, error)             { return nil, nil }
+func (f *fakeDriver) Get(url string) error                         { return nil }
+func (f *fakeDriver) CurrentURL() (string, error)                  { return "", nil }
+func (f *fakeDriver) Title() (string, error)                       { return "", nil }
+func (f *fakeDriver) PageSource() (string, error)                  { return "", nil }
+func (f *fakeDriver) Back() error                                  { return nil }
+func (f *fakeDriver) Forward() error                               { return nil }
+func (f *fakeDriver) Refresh() error                               { return nil }
+
+func (f *fakeDriver) FindElement(by selenium.By, value string) (selenium.WebElement, error) {
+	return nil, nil
+}
+
+func (f *fakeDriver) FindElements(by selenium.By, value string) ([]selenium.WebElement, error) {
+	return nil, nil
+}
+
+func (f *fakeDriver) ExecuteScript(script string, args []interface{}) (interface{}, error) {
+	return nil, nil
+}
+
+func (f *fakeDriver) ExecuteAsyncScript(script string, args []interface{}) (interface{}, error) {
+	return nil, nil
+}
+
+func (f *fakeDriver) Screenshot() ([]byte, error)                       { return nil, nil }
+func (f *fakeDriver) ScreenshotRect(rect selenium.Rect) ([]byte, error) { return nil, nil }
+
+func (f *fakeDriver) PerformActions(actions []selenium.InputSource) error { return nil }
+func (f *fakeDriver) ReleaseActions() error                               { return nil }
+func (f *fakeDriver) DragAndDrop(src, dst selenium.WebElement) error      { return nil }
+func (f *fakeDriver) DoubleClick(elem selenium.WebElement) error          { return nil }
+func (f *fakeDriver) HoldKey(key string) (func() error, error) {
+	return func() error { return nil }, nil
+}
+
+func (f *fakeDriver) Quit() error { return nil }
+
+func (f *fakeDriver) GetCookies() ([]selenium.Cookie, error) {
+	return f.cookies, nil
+}
+
+func (f *fakeDriver) AddCookie(c *selenium.Cookie) error {
+	f.cookies = append(f.cookies, *c)
+	return nil
+}
+
+func (f *fakeDriver) DeleteCookie(name string) error {
+	for i, c := range f.cookies {
+		if c.Name == name {
+			f.cookies = append(f.cookies[:i], f.cookies[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func mustParse(t *testing.T, raw string) *url.URL {
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func TestDomainSuffixScoping(t *testing.T) {
+	jar := New(&fakeDriver{})
+
+	jar.SetCookies(mustParse(t, "https://www.example.com/"), []*http.Cookie{
+		{Name: "session", Value: "abc", Domain: "example.com", Path: "/"},
+	})
+
+	got := jar.Cookies(mustParse(t, "https://other.example.com/"))
+	if len(got) != 1 || got[0].Value != "abc" {
+		t.Fatalf("cookie not visible to sibling subdomain: %v", got)
+	}
+
+	got = jar.Cookies(mustParse(t, "https://example.org/"))
+	if len(got) != 0 {
+		t.Fatalf("cookie leaked to unrelated domain: %v", got)
+	}
+}
+
+func TestSecureAndHttpOnlyFlags(t *testing.T) {
+	wd := &fakeDriver{}
+	jar := New(wd)
+
+	jar.SetCookies(mustParse(t, "https://example.com/account"), []*http.Cookie{
+		{Name: "csrf", Value: "xyz", Secure: true, HttpOnly: true},
+	})
+
+	cookies, err := wd.GetCookies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cookies) != 1 || !cookies[0].Secure {
+		t.Fatalf("secure flag not propagated: %v", cookies)
+	}
+	if !cookies[0].HttpOnly {
+		t.Fatalf("httpOnly flag not propagated: %v", cookies)
+	}
+	if cookies[0].Domain != "example.com" {
+		t.Fatalf("host-only cookie should scope to exact host, got %q", cookies[0].Domain)
+	}
+}
+
+func TestHostOnlyCookieNotBroadenedToRegistrableDomain(t *testing.T) {
+	wd := &fakeDriver{}
+	jar := New(wd)
+
+	jar.SetCookies(mustParse(t, "https://login.example.com/signin"), []*http.Cookie{
+		{Name: "sid", Value: "abc"},
+	})
+
+	cookies, err := wd.GetCookies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cookies) != 1 || cookies[0].Domain != "login.example.com" {
+		t.Fatalf("host-only cookie should keep its exact host as Domain, got %v", cookies)
+	}
+
+	got := jar.Cookies(mustParse(t, "https://evil.example.com/"))
+	if len(got) != 0 {
+		t.Fatalf("host-only cookie leaked to unrelated sibling subdomain: %v", got)
+	}
+}
+
+func TestExpiredCookieFiltered(t *testing.T) {
+	wd := &fakeDriver{
+		cookies: []selenium.Cookie{
+			{Name: "stale", Value: "1", Domain: "example.com", Expiry: time.Now().Add(-time.Hour).Unix()},
+			{Name: "fresh", Value: "2", Domain: "example.com", Expiry: time.Now().Add(time.Hour).Unix()},
+		},
+	}
+	jar := New(wd)
+
+	got := jar.Cookies(mustParse(t, "https://example.com/"))
+	if len(got) != 1 || got[0].Name != "fresh" {
+		t.Fatalf("expired cookie should be filtered out: %v", got)
+	}
+}
+
+func TestCrossOriginIsolation(t *testing.T) {
+	jar := New(&fakeDriver{})
+
+	jar.SetCookies(mustParse(t, "https://a.com/"), []*http.Cookie{{Name: "a", Value: "1"}})
+	jar.SetCookies(mustParse(t, "https://b.com/"), []*http.Cookie{{Name: "b", Value: "2"}})
+
+	gotA := jar.Cookies(mustParse(t, "https://a.com/"))
+	if len(gotA) != 1 || gotA[0].Name != "a" {
+		t.Fatalf("cross-origin cookie leaked into a.com: %v", gotA)
+	}
+
+	gotB := jar.Cookies(mustParse(t, "https://b.com/"))
+	if len(gotB) != 1 || gotB[0].Name != "b" {
+		t.Fatalf("cross-origin cookie leaked into b.com: %v", gotB)
+	}
+}
@@ -0,0 +1,377 @@
+// Package crawler drives a browser through a selenium.WebDriver session to
+// crawl pages that need JavaScript execution to render, in the style of a
+// headless HTTP crawler like colly.
+package crawler
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BenHall/go-selenium"
+)
+
+// Element is a single DOM element matched by an OnHTML selector.
+type Element struct {
+	wd       selenium.WebDriver
+	elem     selenium.WebElement
+	Selector string
+}
+
+// Text returns the element's rendered text.
+func (e *Element) Text() (string, error) {
+	return e.elem.Text()
+}
+
+// Attr returns the value of the given attribute, read via JavaScript since
+// the JSON wire protocol has no dedicated endpoint for it.
+func (e *Element) Attr(name string) (string, error) {
+	reply, err := e.wd.ExecuteScript("return arguments[0].getAttribute(arguments[1]);", []interface{}{e.elem, name})
+	if err != nil {
+		return "", err
+	}
+	s, _ := reply.(string)
+	return s, nil
+}
+
+// HTMLCallback is invoked for each element matching an OnHTML selector.
+type HTMLCallback func(e *Element)
+
+// ResponseCallback is invoked once a visited page has finished loading,
+// with its rendered HTML source.
+type ResponseCallback func(url string, source string)
+
+// ErrorCallback is invoked when visiting a URL fails.
+type ErrorCallback func(url string, err error)
+
+// ReadyFunc reports whether the current page is ready to be scraped. It is
+// polled via WaitReady after each navigation.
+type ReadyFunc func(wd selenium.WebDriver) (bool, error)
+
+// Option configures a Collector.
+type Option func(*Collector)
+
+// MaxDepth bounds how many link-hops Visit/VisitAll will follow from a seed
+// URL. The default, 0, means links found on a page are not followed
+// automatically.
+func MaxDepth(depth int) Option {
+	return func(c *Collector) { c.maxDepth = depth }
+}
+
+// SameDomain restricts automatic link-following to URLs sharing a host with
+// the page that linked to them.
+func SameDomain() Option {
+	return func(c *Collector) { c.sameDomain = true }
+}
+
+// RateLimit bounds how often the collector will issue a Get against the
+// same host.
+func RateLimit(d time.Duration) Option {
+	return func(c *Collector) { c.rateLimit = d }
+}
+
+// RespectRobotsTxt makes the collector fetch and honor each host's
+// robots.txt before visiting a path on it.
+func RespectRobotsTxt() Option {
+	return func(c *Collector) { c.respectRobots = true }
+}
+
+// WaitReady polls ready (up to timeout) after each navigation before
+// treating the page as loaded, for pages whose content renders
+// asynchronously.
+func WaitReady(timeout time.Duration, ready ReadyFunc) Option {
+	return func(c *Collector) {
+		c.ready = ready
+		c.readyTimeout = timeout
+	}
+}
+
+// Collector drives a WebDriver through a crawl, dispatching matched
+// elements and responses to registered callbacks.
+type Collector struct {
+	wd selenium.WebDriver
+
+	maxDepth      int
+	sameDomain    bool
+	rateLimit     time.Duration
+	respectRobots bool
+	ready         ReadyFunc
+	readyTimeout  time.Duration
+
+	htmlCallbacks     []htmlHandler
+	responseCallbacks []ResponseCallback
+	errorCallbacks    []ErrorCallback
+
+	mu        sync.Mutex
+	seen      map[string]bool
+	lastFetch map[string]time.Time
+	robots    map[string]*robotsRules
+}
+
+type htmlHandler struct {
+	selector string
+	fn       HTMLCallback
+}
+
+// New returns a Collector that drives wd.
+func New(wd selenium.WebDriver, opts ...Option) *Collector {
+	c := &Collector{
+		wd:        wd,
+		seen:      make(map[string]bool),
+		lastFetch: make(map[string]time.Time),
+		robots:    make(map[string]*robotsRules),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// OnHTML registers fn to run for every element matching selector on every
+// visited page.
+func (c *Collector) OnHTML(selector string, fn HTMLCallback) {
+	c.htmlCallbacks = append(c.htmlCallbacks, htmlHandler{selector, fn})
+}
+
+// OnResponse registers fn to run once per visited page, after it has
+// loaded.
+func (c *Collector) OnResponse(fn ResponseCallback) {
+	c.responseCallbacks = append(c.responseCallbacks, fn)
+}
+
+// OnError registers fn to run whenever visiting a URL fails.
+func (c *Collector) OnError(fn ErrorCallback) {
+	c.errorCallbacks = append(c.errorCallbacks, fn)
+}
+
+// Visit drives the browser to rawURL, dispatching callbacks for it and,
+// within MaxDepth, for the links it discovers.
+func (c *Collector) Visit(rawURL string) error {
+	return c.visit(rawURL, 0)
+}
+
+// VisitAll visits the URLs in urls in order, stopping at and returning the
+// first error a Visit returns.
+func (c *Collector) VisitAll(urls []string) error {
+	for _, u := range urls {
+		if err := c.Visit(u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Collector) visit(rawURL string, depth int) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		c.fireError(rawURL, err)
+		return err
+	}
+
+	c.mu.Lock()
+	if c.seen[rawURL] {
+		c.mu.Unlock()
+		return nil
+	}
+	c.seen[rawURL] = true
+	c.mu.Unlock()
+
+	if c.respectRobots {
+		allowed, err := c.robotsAllowed(u)
+		if err != nil {
+			c.fireError(rawURL, err)
+			return err
+		}
+		if !allowed {
+			return nil
+		}
+	}
+
+	c.throttle(u.Host)
+
+	if err := c.wd.Get(rawURL); err != nil {
+		c.fireError(rawURL, err)
+		return err
+	}
+
+	if c.ready != nil {
+		if err := c.waitReady(); err != nil {
+			c.fireError(rawURL, err)
+			return err
+		}
+	}
+
+	source, err := c.wd.PageSource()
+	if err != nil {
+		c.fireError(rawURL, err)
+		return err
+	}
+
+	for _, cb := range c.responseCallbacks {
+		cb(rawURL, source)
+	}
+
+	for _, hc := range c.htmlCallbacks {
+		elems, err := c.wd.FindElements(selenium.ByCSSSelector, hc.selector)
+		if err != nil {
+			c.fireError(rawURL, err)
+			continue
+		}
+		for _, elem := range elems {
+			hc.fn(&Element{wd: c.wd, elem: elem, Selector: hc.selector})
+		}
+	}
+
+	if depth >= c.maxDepth {
+		return nil
+	}
+
+	links, err := c.discoverLinks(u)
+	if err != nil {
+		c.fireError(rawURL, err)
+		return nil
+	}
+	for _, link := range links {
+		c.visit(link, depth+1)
+	}
+	return nil
+}
+
+func (c *Collector) fireError(url string, err error) {
+	for _, cb := range c.errorCallbacks {
+		cb(url, err)
+	}
+}
+
+func (c *Collector) throttle(host string) {
+	if c.rateLimit == 0 {
+		return
+	}
+	c.mu.Lock()
+	wait := c.rateLimit - time.Since(c.lastFetch[host])
+	if wait < 0 {
+		wait = 0
+	}
+	c.lastFetch[host] = time.Now().Add(wait)
+	c.mu.Unlock()
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+}
+
+func (c *Collector) waitReady() error {
+	deadline := time.Now().Add(c.readyTimeout)
+	for {
+		ok, err := c.ready(c.wd)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("crawler: page not ready after %s", c.readyTimeout)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func (c *Collector) discoverLinks(base *url.URL) ([]string, error) {
+	anchors, err := c.wd.FindElements(selenium.ByTagName, "a")
+	if err != nil {
+		return nil, err
+	}
+
+	var links []string
+	for _, a := range anchors {
+		reply, err := c.wd.ExecuteScript("return arguments[0].href;", []interface{}{a})
+		if err != nil {
+			continue
+		}
+		href, ok := reply.(string)
+		if !ok || href == "" {
+			continue
+		}
+		u, err := url.Parse(href)
+		if err != nil {
+			continue
+		}
+		if c.sameDomain && u.Host != base.Host {
+			continue
+		}
+		links = append(links, href)
+	}
+	return links, nil
+}
+
+// robotsRules is the subset of a robots.txt we honor: the Disallow paths
+// that apply to the "*" user agent.
+type robotsRules struct {
+	disallow []string
+}
+
+func (c *Collector) robotsAllowed(u *url.URL) (bool, error) {
+	c.mu.Lock()
+	rules, ok := c.robots[u.Host]
+	c.mu.Unlock()
+
+	if !ok {
+		fetched, err := fetchRobots(u)
+		if err != nil {
+			return false, err
+		}
+		c.mu.Lock()
+		c.robots[u.Host] = fetched
+		c.mu.Unlock()
+		rules = fetched
+	}
+
+	for _, d := range rules.disallow {
+		if strings.HasPrefix(u.Path, d) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// fetchRobots fetches and parses u's robots.txt. An unreachable or missing
+// robots.txt is treated as allow-all, matching common crawler behavior.
+func fetchRobots(u *url.URL) (*robotsRules, error) {
+	resp, err := http.Get(fmt.Sprintf("%s://%s/robots.txt", u.Scheme, u.Host))
+	if err != nil {
+		return &robotsRules{}, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &robotsRules{}, nil
+	}
+
+	rules := &robotsRules{}
+	applies := false
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(parts[0]))
+		value := strings.TrimSpace(parts[1])
+		switch key {
+		case "user-agent":
+			applies = value == "*"
+		case "disallow":
+			if applies && value != "" {
+				rules.disallow = append(rules.disallow, value)
+			}
+		}
+	}
+	return rules, scanner.Err()
+}
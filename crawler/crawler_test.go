@@ -0,0 +1,211 @@
+package crawler
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/BenHall/go-selenium"
+)
+
+// fakePage is what fakeDriver "renders" when Get navigates to its URL.
+type fakePage struct {
+	source  string
+	links   []string
+	matches map[string][]string
+}
+
+// fakeElement is a minimal selenium.WebElement backing fakeDriver.
+type fakeElement struct {
+	text string
+	href string
+}
+
+func (e *fakeElement) Click() error                           { return nil }
+func (e *fakeElement) SendKeys(keys string) error              { return nil }
+func (e *fakeElement) Text() (string, error)                   { return e.text, nil }
+func (e *fakeElement) IsSelected() (bool, error)                { return false, nil }
+func (e *fakeElement) Location() (*selenium.Point, error)       { return &selenium.Point{}, nil }
+func (e *fakeElement) LocationInView() (*selenium.Point, error) { return &selenium.Point{}, nil }
+func (e *fakeElement) Size() (*selenium.Size, error)            { return &selenium.Size{}, nil }
+func (e *fakeElement) Screenshot() ([]byte, error)              { return nil, nil }
+
+// fakeDriver is a minimal selenium.WebDriver, just enough to exercise
+// Collector without a live browser.
+type fakeDriver struct {
+	pages   map[string]fakePage
+	current string
+}
+
+func (f *fakeDriver) Status() (*selenium.Status, error)            { return nil, nil }
+func (f *fakeDriver) Capabilities() (selenium.Capabilities, error) { return nil, nil }
+func (f *fakeDriver) SetAsyncScriptTimeout(ms uint) error          { return nil }
+func (f *fakeDriver) SetImplicitWaitTimeout(ms uint) error         { return nil }
+func (f *fakeDriver) CurrentWindowHandle() (string, error)         { return "", nil }
+func (f *fakeDriver) WindowHandles() ([]string, error)             { return nil, nil }
+
+func (f *fakeDriver) Get(url string) error {
+	if _, ok := f.pages[url]; !ok {
+		return fmt.Errorf("no such page: %s", url)
+	}
+	f.current = url
+	return nil
+}
+
+func (f *fakeDriver) CurrentURL() (string, error) { return f.current, nil }
+func (f *fakeDriver) Title() (string, error)       { return "", nil }
+func (f *fakeDriver) PageSource() (string, error)  { return f.pages[f.current].source, nil }
+func (f *fakeDriver) Back() error                  { return nil }
+func (f *fakeDriver) Forward() error               { return nil }
+func (f *fakeDriver) Refresh() error                { return nil }
+
+func (f *fakeDriver) FindElement(by selenium.By, value string) (selenium.WebElement, error) {
+	elems, err := f.FindElements(by, value)
+	if err != nil {
+		return nil, err
+	}
+	if len(elems) == 0 {
+		return nil, fmt.Errorf("no such element")
+	}
+	return elems[0], nil
+}
+
+func (f *fakeDriver) FindElements(by selenium.By, value string) ([]selenium.WebElement, error) {
+	page := f.pages[f.current]
+
+	if by == selenium.ByTagName && value == "a" {
+		elems := make([]selenium.WebElement, len(page.links))
+		for i, href := range page.links {
+			elems[i] = &fakeElement{href: href}
+		}
+		return elems, nil
+	}
+
+	texts := page.matches[value]
+	elems := make([]selenium.WebElement, len(texts))
+	for i, text := range texts {
+		elems[i] = &fakeElement{text: text}
+	}
+	return elems, nil
+}
+
+func (f *fakeDriver) GetCookies() ([]selenium.Cookie, error) { return nil, nil }
+func (f *fakeDriver) AddCookie(c *selenium.Cookie) error     { return nil }
+func (f *fakeDriver) DeleteCookie(name string) error         { return nil }
+
+func (f *fakeDriver) ExecuteScript(script string, args []interface{}) (interface{}, error) {
+	if len(args) == 1 {
+		if elem, ok := args[0].(*fakeElement); ok {
+			return elem.href, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeDriver) ExecuteAsyncScript(script string, args []interface{}) (interface{}, error) {
+	return nil, nil
+}
+
+func (f *fakeDriver) Screenshot() ([]byte, error)                      { return nil, nil }
+func (f *fakeDriver) ScreenshotRect(rect selenium.Rect) ([]byte, error) { return nil, nil }
+
+func (f *fakeDriver) PerformActions(actions []selenium.InputSource) error { return nil }
+func (f *fakeDriver) ReleaseActions() error                               { return nil }
+func (f *fakeDriver) DragAndDrop(src, dst selenium.WebElement) error      { return nil }
+func (f *fakeDriver) DoubleClick(elem selenium.WebElement) error          { return nil }
+func (f *fakeDriver) HoldKey(key string) (func() error, error) {
+	return func() error { return nil }, nil
+}
+
+func (f *fakeDriver) Quit() error { return nil }
+
+func TestVisitFollowsLinksWithinDepthAndDomain(t *testing.T) {
+	wd := &fakeDriver{pages: map[string]fakePage{
+		"http://example.com/":  {source: "home", links: []string{"http://example.com/a", "http://other.com/x"}},
+		"http://example.com/a": {source: "a", links: []string{"http://example.com/b"}},
+		"http://example.com/b": {source: "b"},
+		"http://other.com/x":   {source: "x"},
+	}}
+
+	var visited []string
+	c := New(wd, MaxDepth(1), SameDomain())
+	c.OnResponse(func(url, source string) {
+		visited = append(visited, url)
+	})
+
+	if err := c.Visit("http://example.com/"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]bool{"http://example.com/": true, "http://example.com/a": true}
+	if len(visited) != len(want) {
+		t.Fatalf("visited %v, want exactly %v", visited, want)
+	}
+	for _, u := range visited {
+		if !want[u] {
+			t.Fatalf("visited out-of-scope URL %s", u)
+		}
+	}
+}
+
+func TestOnHTMLDispatchesMatchingElements(t *testing.T) {
+	wd := &fakeDriver{pages: map[string]fakePage{
+		"http://example.com/": {
+			source:  "<html></html>",
+			matches: map[string][]string{"h1": {"Hello", "World"}},
+		},
+	}}
+
+	var texts []string
+	c := New(wd)
+	c.OnHTML("h1", func(e *Element) {
+		text, err := e.Text()
+		if err != nil {
+			t.Fatal(err)
+		}
+		texts = append(texts, text)
+	})
+
+	if err := c.Visit("http://example.com/"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(texts) != 2 || texts[0] != "Hello" || texts[1] != "World" {
+		t.Fatalf("got %v", texts)
+	}
+}
+
+func TestVisitDedupesSeenURLs(t *testing.T) {
+	wd := &fakeDriver{pages: map[string]fakePage{
+		"http://example.com/": {source: "home"},
+	}}
+
+	var count int
+	c := New(wd)
+	c.OnResponse(func(url, source string) { count++ })
+
+	if err := c.Visit("http://example.com/"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Visit("http://example.com/"); err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 1 {
+		t.Fatalf("expected a single visit, got %d", count)
+	}
+}
+
+func TestOnErrorFiresForBadURL(t *testing.T) {
+	wd := &fakeDriver{pages: map[string]fakePage{}}
+
+	var gotErr error
+	c := New(wd)
+	c.OnError(func(url string, err error) { gotErr = err })
+
+	if err := c.Visit("http://example.com/missing"); err == nil {
+		t.Fatal("expected Visit to return an error")
+	}
+	if gotErr == nil {
+		t.Fatal("expected OnError to fire")
+	}
+}
@@ -0,0 +1,543 @@
+// Package selenium implements a client for the Selenium WebDriver JSON wire
+// protocol, letting Go programs drive a real browser through a Selenium
+// server or Selenium Grid.
+package selenium
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// Trace controls whether HTTP requests and responses are logged to stdout.
+// It is normally wired up to a `-trace` flag by callers.
+var Trace = false
+
+// defaultExecutor is used when NewRemote is called with an empty executor
+// URL, pointing at a Selenium server running on the default port.
+const defaultExecutor = "http://127.0.0.1:4444/wd/hub"
+
+// By identifies the strategy used to locate an element on the page.
+type By string
+
+// Locator strategies understood by the remote end.
+const (
+	ById              By = "id"
+	ByXPATH           By = "xpath"
+	ByLinkText        By = "link text"
+	ByPartialLinkText By = "partial link text"
+	ByName            By = "name"
+	ByTagName         By = "tag name"
+	ByClassName       By = "class name"
+	ByCSSSelector     By = "css selector"
+)
+
+// Capabilities describes the desired capabilities of a browser session, sent
+// when the session is created.
+type Capabilities map[string]interface{}
+
+// Point is a location on the page, in pixels. Location reports it relative
+// to the top-left corner of the document, LocationInView relative to the
+// top-left corner of the viewport.
+type Point struct {
+	X, Y int
+}
+
+// Size is the width and height of an element, in pixels.
+type Size struct {
+	Width, Height int
+}
+
+// Rect describes a pixel region of the page, used to clip a screenshot down
+// to a sub-image.
+type Rect struct {
+	Top, Left, Width, Height int
+}
+
+// Cookie is a single HTTP cookie as understood by the remote end.
+type Cookie struct {
+	Name     string      `json:"name"`
+	Value    string      `json:"value"`
+	Path     string      `json:"path,omitempty"`
+	Domain   string      `json:"domain,omitempty"`
+	Secure   bool        `json:"secure,omitempty"`
+	HttpOnly bool        `json:"httpOnly,omitempty"`
+	Expiry   interface{} `json:"expiry,omitempty"`
+}
+
+// Status is the reply to the server's /status endpoint, describing the
+// remote end currently in use.
+type Status struct {
+	Build struct {
+		Version, Revision, Time string
+	}
+	OS struct {
+		Arch, Name, Version string
+	}
+}
+
+// WebDriver is a single browser session.
+type WebDriver interface {
+	// Status returns information about the remote end.
+	Status() (*Status, error)
+	// Capabilities returns the actual capabilities of this session.
+	Capabilities() (Capabilities, error)
+
+	SetAsyncScriptTimeout(ms uint) error
+	SetImplicitWaitTimeout(ms uint) error
+
+	CurrentWindowHandle() (string, error)
+	WindowHandles() ([]string, error)
+
+	// Get navigates to the given URL.
+	Get(url string) error
+	CurrentURL() (string, error)
+	Title() (string, error)
+	PageSource() (string, error)
+	Back() error
+	Forward() error
+	Refresh() error
+
+	FindElement(by By, value string) (WebElement, error)
+	FindElements(by By, value string) ([]WebElement, error)
+
+	GetCookies() ([]Cookie, error)
+	AddCookie(cookie *Cookie) error
+	DeleteCookie(name string) error
+
+	ExecuteScript(script string, args []interface{}) (interface{}, error)
+	ExecuteAsyncScript(script string, args []interface{}) (interface{}, error)
+
+	// Screenshot returns a PNG-encoded screenshot of the current page.
+	Screenshot() ([]byte, error)
+	// ScreenshotRect returns a PNG-encoded screenshot of the current page,
+	// cropped to rect.
+	ScreenshotRect(rect Rect) ([]byte, error)
+
+	// PerformActions sends a W3C Actions sequence, ticking every InputSource
+	// in lockstep.
+	PerformActions(actions []InputSource) error
+	// ReleaseActions releases all keys and pointer buttons currently held
+	// down as a result of PerformActions.
+	ReleaseActions() error
+	// DragAndDrop drags src to dst using a synthesized pointer sequence.
+	DragAndDrop(src, dst WebElement) error
+	// DoubleClick performs a synthesized double-click on elem.
+	DoubleClick(elem WebElement) error
+	// HoldKey presses key down and returns a function that releases it.
+	HoldKey(key string) (release func() error, err error)
+
+	// Quit ends the session, releasing the underlying browser.
+	Quit() error
+}
+
+// WebElement is a single DOM element found within a WebDriver session.
+type WebElement interface {
+	Click() error
+	SendKeys(keys string) error
+	Text() (string, error)
+	IsSelected() (bool, error)
+	Location() (*Point, error)
+	LocationInView() (*Point, error)
+	Size() (*Size, error)
+	// Screenshot returns a PNG-encoded screenshot cropped to this element's
+	// bounding box.
+	Screenshot() ([]byte, error)
+}
+
+// remoteWebDriver is the default WebDriver implementation. It speaks the
+// JSON wire protocol to a remote Selenium server over HTTP.
+type remoteWebDriver struct {
+	id           string
+	capabilities Capabilities
+	executor     string
+}
+
+// NewRemote starts a new session against executor (the URL of a Selenium
+// server, e.g. "http://127.0.0.1:4444/wd/hub") using the given desired
+// capabilities. If executor is empty, defaultExecutor is used.
+func NewRemote(capabilities Capabilities, executor string) (WebDriver, error) {
+	if executor == "" {
+		executor = defaultExecutor
+	}
+	wd := &remoteWebDriver{capabilities: capabilities, executor: executor}
+	if _, err := wd.NewSession(); err != nil {
+		return nil, err
+	}
+	return wd, nil
+}
+
+func (wd *remoteWebDriver) requestURL(format string, args ...interface{}) string {
+	return wd.executor + fmt.Sprintf(format, args...)
+}
+
+// execute performs a single HTTP request against the remote end and returns
+// the raw response body. It is a convenience wrapper around doContext using
+// a background context; callers that need cancellation or a deadline should
+// use the *Context method variants instead.
+func (wd *remoteWebDriver) execute(method, url string, data []byte) ([]byte, error) {
+	return wd.doContext(context.Background(), method, url, data)
+}
+
+// doContext is the single choke point every request goes through. All
+// *Context methods, and every non-context method via execute, end up here,
+// so a context deadline or cancellation aborts the underlying HTTP request
+// no matter how it was reached.
+func (wd *remoteWebDriver) doContext(ctx context.Context, method, url string, data []byte) ([]byte, error) {
+	if Trace {
+		fmt.Printf("-> %s %s\n%s\n", method, url, data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	buf, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if Trace {
+		fmt.Printf("<- %s\n", buf)
+	}
+
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("selenium: %s %s: %s", method, url, resp.Status)
+	}
+	return buf, nil
+}
+
+// decode executes the request like execute, then unmarshals the reply's
+// "value" field into out.
+func (wd *remoteWebDriver) decode(method, url string, data []byte, out interface{}) error {
+	return wd.decodeContext(context.Background(), method, url, data, out)
+}
+
+// decodeContext is decode's context-aware counterpart.
+func (wd *remoteWebDriver) decodeContext(ctx context.Context, method, url string, data []byte, out interface{}) error {
+	buf, err := wd.doContext(ctx, method, url, data)
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	reply := struct {
+		Value json.RawMessage
+	}{}
+	if err := json.Unmarshal(buf, &reply); err != nil {
+		return err
+	}
+	return json.Unmarshal(reply.Value, out)
+}
+
+// NewSession creates the remote session and records its id. It is exposed
+// (rather than folded into NewRemote) so tests can exercise session
+// creation directly.
+func (wd *remoteWebDriver) NewSession() (string, error) {
+	params := map[string]interface{}{"desiredCapabilities": wd.capabilities}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return "", err
+	}
+
+	buf, err := wd.execute("POST", wd.executor+"/session", data)
+	if err != nil {
+		return "", err
+	}
+
+	reply := struct {
+		SessionId string
+	}{}
+	if err := json.Unmarshal(buf, &reply); err != nil {
+		return "", err
+	}
+
+	wd.id = reply.SessionId
+	return wd.id, nil
+}
+
+func (wd *remoteWebDriver) Status() (*Status, error) {
+	status := new(Status)
+	err := wd.decode("GET", wd.executor+"/status", nil, status)
+	return status, err
+}
+
+func (wd *remoteWebDriver) Capabilities() (Capabilities, error) {
+	c := make(Capabilities)
+	err := wd.decode("GET", wd.requestURL("/session/%s", wd.id), nil, &c)
+	return c, err
+}
+
+func (wd *remoteWebDriver) SetAsyncScriptTimeout(ms uint) error {
+	data, err := json.Marshal(map[string]uint{"ms": ms})
+	if err != nil {
+		return err
+	}
+	_, err = wd.execute("POST", wd.requestURL("/session/%s/timeouts/async_script", wd.id), data)
+	return err
+}
+
+func (wd *remoteWebDriver) SetImplicitWaitTimeout(ms uint) error {
+	data, err := json.Marshal(map[string]uint{"ms": ms})
+	if err != nil {
+		return err
+	}
+	_, err = wd.execute("POST", wd.requestURL("/session/%s/timeouts/implicit_wait", wd.id), data)
+	return err
+}
+
+func (wd *remoteWebDriver) CurrentWindowHandle() (string, error) {
+	var handle string
+	err := wd.decode("GET", wd.requestURL("/session/%s/window_handle", wd.id), nil, &handle)
+	return handle, err
+}
+
+func (wd *remoteWebDriver) WindowHandles() ([]string, error) {
+	var handles []string
+	err := wd.decode("GET", wd.requestURL("/session/%s/window_handles", wd.id), nil, &handles)
+	return handles, err
+}
+
+func (wd *remoteWebDriver) Get(url string) error {
+	data, err := json.Marshal(map[string]string{"url": url})
+	if err != nil {
+		return err
+	}
+	_, err = wd.execute("POST", wd.requestURL("/session/%s/url", wd.id), data)
+	return err
+}
+
+func (wd *remoteWebDriver) CurrentURL() (string, error) {
+	var url string
+	err := wd.decode("GET", wd.requestURL("/session/%s/url", wd.id), nil, &url)
+	return url, err
+}
+
+func (wd *remoteWebDriver) Title() (string, error) {
+	var title string
+	err := wd.decode("GET", wd.requestURL("/session/%s/title", wd.id), nil, &title)
+	return title, err
+}
+
+func (wd *remoteWebDriver) PageSource() (string, error) {
+	var source string
+	err := wd.decode("GET", wd.requestURL("/session/%s/source", wd.id), nil, &source)
+	return source, err
+}
+
+func (wd *remoteWebDriver) Back() error {
+	_, err := wd.execute("POST", wd.requestURL("/session/%s/back", wd.id), nil)
+	return err
+}
+
+func (wd *remoteWebDriver) Forward() error {
+	_, err := wd.execute("POST", wd.requestURL("/session/%s/forward", wd.id), nil)
+	return err
+}
+
+func (wd *remoteWebDriver) Refresh() error {
+	_, err := wd.execute("POST", wd.requestURL("/session/%s/refresh", wd.id), nil)
+	return err
+}
+
+// elementRef is the wire representation of a single element reference.
+type elementRef struct {
+	Id string `json:"ELEMENT"`
+}
+
+func (wd *remoteWebDriver) FindElement(by By, value string) (WebElement, error) {
+	data, err := json.Marshal(map[string]string{"using": string(by), "value": value})
+	if err != nil {
+		return nil, err
+	}
+	var ref elementRef
+	if err := wd.decode("POST", wd.requestURL("/session/%s/element", wd.id), data, &ref); err != nil {
+		return nil, err
+	}
+	return &remoteWE{id: ref.Id, parent: wd}, nil
+}
+
+func (wd *remoteWebDriver) FindElements(by By, value string) ([]WebElement, error) {
+	data, err := json.Marshal(map[string]string{"using": string(by), "value": value})
+	if err != nil {
+		return nil, err
+	}
+	var refs []elementRef
+	if err := wd.decode("POST", wd.requestURL("/session/%s/elements", wd.id), data, &refs); err != nil {
+		return nil, err
+	}
+	elems := make([]WebElement, len(refs))
+	for i, ref := range refs {
+		elems[i] = &remoteWE{id: ref.Id, parent: wd}
+	}
+	return elems, nil
+}
+
+func (wd *remoteWebDriver) GetCookies() ([]Cookie, error) {
+	var cookies []Cookie
+	err := wd.decode("GET", wd.requestURL("/session/%s/cookie", wd.id), nil, &cookies)
+	return cookies, err
+}
+
+func (wd *remoteWebDriver) AddCookie(cookie *Cookie) error {
+	data, err := json.Marshal(map[string]*Cookie{"cookie": cookie})
+	if err != nil {
+		return err
+	}
+	_, err = wd.execute("POST", wd.requestURL("/session/%s/cookie", wd.id), data)
+	return err
+}
+
+func (wd *remoteWebDriver) DeleteCookie(name string) error {
+	_, err := wd.execute("DELETE", wd.requestURL("/session/%s/cookie/%s", wd.id, name), nil)
+	return err
+}
+
+func (wd *remoteWebDriver) ExecuteScript(script string, args []interface{}) (interface{}, error) {
+	if args == nil {
+		args = make([]interface{}, 0)
+	}
+	data, err := json.Marshal(map[string]interface{}{"script": script, "args": args})
+	if err != nil {
+		return nil, err
+	}
+	var reply interface{}
+	err = wd.decode("POST", wd.requestURL("/session/%s/execute", wd.id), data, &reply)
+	return reply, err
+}
+
+func (wd *remoteWebDriver) Screenshot() ([]byte, error) {
+	var encoded string
+	if err := wd.decode("GET", wd.requestURL("/session/%s/screenshot", wd.id), nil, &encoded); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(encoded)
+}
+
+func (wd *remoteWebDriver) ScreenshotRect(rect Rect) ([]byte, error) {
+	full, err := wd.Screenshot()
+	if err != nil {
+		return nil, err
+	}
+	return cropPNG(full, rect)
+}
+
+// cropPNG decodes a PNG-encoded screenshot and re-encodes the sub-image
+// bounded by rect.
+func cropPNG(data []byte, rect Rect) ([]byte, error) {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	cropper, ok := img.(interface {
+		SubImage(r image.Rectangle) image.Image
+	})
+	if !ok {
+		return nil, fmt.Errorf("selenium: screenshot image does not support cropping")
+	}
+
+	bounds := image.Rect(rect.Left, rect.Top, rect.Left+rect.Width, rect.Top+rect.Height)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, cropper.SubImage(bounds)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (wd *remoteWebDriver) Quit() error {
+	_, err := wd.execute("DELETE", wd.requestURL("/session/%s", wd.id), nil)
+	wd.id = ""
+	return err
+}
+
+// remoteWE is the default WebElement implementation, backed by a session on
+// a remoteWebDriver.
+type remoteWE struct {
+	id     string
+	parent *remoteWebDriver
+}
+
+// MarshalJSON encodes elem as a wire element reference, so it can be passed
+// as an ExecuteScript argument.
+func (elem *remoteWE) MarshalJSON() ([]byte, error) {
+	return json.Marshal(elementRef{Id: elem.id})
+}
+
+func (elem *remoteWE) url(suffix string) string {
+	return elem.parent.requestURL("/session/%s/element/%s%s", elem.parent.id, elem.id, suffix)
+}
+
+func (elem *remoteWE) Click() error {
+	_, err := elem.parent.execute("POST", elem.url("/click"), nil)
+	return err
+}
+
+func (elem *remoteWE) SendKeys(keys string) error {
+	data, err := json.Marshal(map[string]interface{}{"value": strings.Split(keys, "")})
+	if err != nil {
+		return err
+	}
+	_, err = elem.parent.execute("POST", elem.url("/value"), data)
+	return err
+}
+
+func (elem *remoteWE) Text() (string, error) {
+	var text string
+	err := elem.parent.decode("GET", elem.url("/text"), nil, &text)
+	return text, err
+}
+
+func (elem *remoteWE) IsSelected() (bool, error) {
+	var selected bool
+	err := elem.parent.decode("GET", elem.url("/selected"), nil, &selected)
+	return selected, err
+}
+
+func (elem *remoteWE) Location() (*Point, error) {
+	p := new(Point)
+	err := elem.parent.decode("GET", elem.url("/location"), nil, p)
+	return p, err
+}
+
+func (elem *remoteWE) LocationInView() (*Point, error) {
+	p := new(Point)
+	err := elem.parent.decode("GET", elem.url("/location_in_view"), nil, p)
+	return p, err
+}
+
+func (elem *remoteWE) Size() (*Size, error) {
+	s := new(Size)
+	err := elem.parent.decode("GET", elem.url("/size"), nil, s)
+	return s, err
+}
+
+// Screenshot returns a PNG-encoded screenshot of the full page, cropped to
+// this element's current bounding box.
+func (elem *remoteWE) Screenshot() ([]byte, error) {
+	loc, err := elem.Location()
+	if err != nil {
+		return nil, err
+	}
+	size, err := elem.Size()
+	if err != nil {
+		return nil, err
+	}
+	return elem.parent.ScreenshotRect(Rect{Top: loc.Y, Left: loc.X, Width: size.Width, Height: size.Height})
+}